@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// authorizeRequest pulls the subject withAuth attached to the request
+// context and runs it through the configured Authorizer for the given
+// bucket/key/op. Every /presign* handler calls this before presigning.
+func authorizeRequest(r *http.Request, bucket, key, op string, expiry time.Duration) error {
+	subject, _ := r.Context().Value(ctxKeySubject).(string)
+	return authorizer.Authorize(r.Context(), AuthzRequest{
+		Subject: subject,
+		Bucket:  bucket,
+		Key:     key,
+		Op:      op,
+		Expiry:  expiry,
+	})
+}
+
+// AuthzRequest is the decision input every /presign* endpoint passes to the
+// configured Authorizer before generating a URL.
+type AuthzRequest struct {
+	Subject string
+	Bucket  string
+	Key     string
+	Op      string // "get" or "put"
+	Expiry  time.Duration
+}
+
+// Authorizer decides whether a subject may perform an operation against a
+// bucket/key. Any returned error, including a transport failure reaching a
+// remote policy engine, is treated as a denial.
+type Authorizer interface {
+	Authorize(ctx context.Context, req AuthzRequest) error
+}
+
+// newAuthorizerFromEnv builds the Authorizer selected by AUTHZ_BACKEND.
+// Defaults to the in-process allowlist backed by POLICY_FILE.
+func newAuthorizerFromEnv() (Authorizer, error) {
+	switch backend := getenv("AUTHZ_BACKEND", "allowlist"); backend {
+	case "allowlist":
+		return newAllowlistAuthorizer(getenv("POLICY_FILE", ""))
+	case "opa":
+		return newOPAAuthorizer(getenv("OPA_URL", ""))
+	default:
+		return nil, fmt.Errorf("unknown AUTHZ_BACKEND %q (want \"allowlist\" or \"opa\")", backend)
+	}
+}
+
+// subjectPolicy is one entry of POLICY_FILE: the buckets/keys (as glob
+// patterns) a subject may touch, and which operations it may perform.
+type subjectPolicy struct {
+	Buckets []string `json:"buckets"`
+	Keys    []string `json:"keys"`
+	Ops     []string `json:"ops"`
+}
+
+type policyFile struct {
+	Policies map[string]subjectPolicy `json:"policies"`
+}
+
+// allowlistAuthorizer enforces a static, in-process POLICY_FILE mapping
+// subjects to the bucket/key glob patterns and operations they're allowed.
+type allowlistAuthorizer struct {
+	policies map[string]subjectPolicy
+
+	// legacyOpen is true when no POLICY_FILE was configured at all, meaning
+	// this deployment predates the authorizer and only ever authenticated
+	// with the static API_TOKEN. In that case legacySubject is let through
+	// unconditionally so upgrading to this commit doesn't 403 every
+	// /presign* call; operators opt into per-subject policies (including
+	// restricting legacySubject) by setting POLICY_FILE.
+	legacyOpen bool
+}
+
+func newAllowlistAuthorizer(policyPath string) (*allowlistAuthorizer, error) {
+	if policyPath == "" {
+		return &allowlistAuthorizer{policies: map[string]subjectPolicy{}, legacyOpen: true}, nil
+	}
+
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading POLICY_FILE: %w", err)
+	}
+
+	var pf policyFile
+	if err := json.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("parsing POLICY_FILE: %w", err)
+	}
+
+	return &allowlistAuthorizer{policies: pf.Policies}, nil
+}
+
+func (a *allowlistAuthorizer) Authorize(ctx context.Context, req AuthzRequest) error {
+	policy, ok := a.policies[req.Subject]
+	if !ok {
+		if a.legacyOpen && req.Subject == legacySubject {
+			return nil
+		}
+		return fmt.Errorf("no policy for subject %q", req.Subject)
+	}
+	if !containsString(policy.Ops, req.Op) {
+		return fmt.Errorf("subject %q is not permitted to %q", req.Subject, req.Op)
+	}
+	if !matchesAny(policy.Buckets, req.Bucket) {
+		return fmt.Errorf("subject %q is not permitted on bucket %q", req.Subject, req.Bucket)
+	}
+	if !matchesAny(policy.Keys, req.Key) {
+		return fmt.Errorf("subject %q is not permitted on key %q", req.Subject, req.Key)
+	}
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if globMatch(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch reports whether value matches pattern. Unlike path.Match, "*"
+// and "?" are allowed to cross "/" boundaries: bucket/key allowlists are
+// prefix-style ("photos/*" should cover every object under "photos/"), and
+// operators authoring POLICY_FILE would not expect "*" to stop at the first
+// path segment the way it does for shell globs.
+func globMatch(pattern, value string) bool {
+	return globMatchRegexp(pattern).MatchString(value)
+}
+
+var globRegexpCache sync.Map // pattern string -> *regexp.Regexp
+
+func globMatchRegexp(pattern string) *regexp.Regexp {
+	if cached, ok := globRegexpCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, c := range pattern {
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re := regexp.MustCompile(sb.String())
+	globRegexpCache.Store(pattern, re)
+	return re
+}
+
+// opaAuthorizer defers the allow/deny decision to an external Open Policy
+// Agent endpoint.
+type opaAuthorizer struct {
+	url    string
+	client *http.Client
+}
+
+func newOPAAuthorizer(opaURL string) (*opaAuthorizer, error) {
+	if opaURL == "" {
+		return nil, fmt.Errorf("OPA_URL is required for AUTHZ_BACKEND=opa")
+	}
+	return &opaAuthorizer{url: opaURL, client: &http.Client{Timeout: 5 * time.Second}}, nil
+}
+
+type opaInput struct {
+	Subject string `json:"subject"`
+	Bucket  string `json:"bucket"`
+	Key     string `json:"key"`
+	Op      string `json:"op"`
+	Expiry  string `json:"expiry"`
+}
+
+type opaRequestBody struct {
+	Input opaInput `json:"input"`
+}
+
+type opaResponseBody struct {
+	Result struct {
+		Allow bool `json:"allow"`
+	} `json:"result"`
+}
+
+func (a *opaAuthorizer) Authorize(ctx context.Context, req AuthzRequest) error {
+	body, err := json.Marshal(opaRequestBody{Input: opaInput{
+		Subject: req.Subject,
+		Bucket:  req.Bucket,
+		Key:     req.Key,
+		Op:      req.Op,
+		Expiry:  req.Expiry.String(),
+	}})
+	if err != nil {
+		return fmt.Errorf("encoding OPA request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building OPA request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("calling OPA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OPA returned status %d", resp.StatusCode)
+	}
+
+	var out opaResponseBody
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("decoding OPA response: %w", err)
+	}
+	if !out.Result.Allow {
+		return fmt.Errorf("denied by policy for subject %q", req.Subject)
+	}
+	return nil
+}
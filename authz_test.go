@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAllowlistAuthorizerAuthorize(t *testing.T) {
+	a := &allowlistAuthorizer{
+		policies: map[string]subjectPolicy{
+			"alice": {
+				Buckets: []string{"photos"},
+				Keys:    []string{"photos/*"},
+				Ops:     []string{"get"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		req     AuthzRequest
+		wantErr bool
+	}{
+		{
+			name: "allowed get under prefix",
+			req:  AuthzRequest{Subject: "alice", Bucket: "photos", Key: "photos/2024/a.jpg", Op: "get"},
+		},
+		{
+			name:    "disallowed op",
+			req:     AuthzRequest{Subject: "alice", Bucket: "photos", Key: "photos/2024/a.jpg", Op: "put"},
+			wantErr: true,
+		},
+		{
+			name:    "disallowed bucket",
+			req:     AuthzRequest{Subject: "alice", Bucket: "other", Key: "photos/2024/a.jpg", Op: "get"},
+			wantErr: true,
+		},
+		{
+			name:    "disallowed key",
+			req:     AuthzRequest{Subject: "alice", Bucket: "photos", Key: "videos/a.mp4", Op: "get"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown subject denied",
+			req:     AuthzRequest{Subject: "mallory", Bucket: "photos", Key: "photos/a.jpg", Op: "get"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := a.Authorize(context.Background(), tt.req)
+			if tt.wantErr && err == nil {
+				t.Fatalf("Authorize() = nil error, want error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Authorize() = %v, want success", err)
+			}
+		})
+	}
+}
+
+func TestAllowlistAuthorizerLegacyOpen(t *testing.T) {
+	open, err := newAllowlistAuthorizer("")
+	if err != nil {
+		t.Fatalf("newAllowlistAuthorizer(\"\") error: %v", err)
+	}
+	req := AuthzRequest{Subject: legacySubject, Bucket: "photos", Key: "a.jpg", Op: "get"}
+	if err := open.Authorize(context.Background(), req); err != nil {
+		t.Fatalf("legacy subject should be allowed with no POLICY_FILE configured, got: %v", err)
+	}
+
+	// A subject other than legacySubject still gets no free pass.
+	req.Subject = "some-sts-subject"
+	if err := open.Authorize(context.Background(), req); err == nil {
+		t.Fatalf("non-legacy subject should still be denied with no POLICY_FILE configured")
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		value    string
+		want     bool
+	}{
+		{name: "prefix glob spans segments", patterns: []string{"photos/*"}, value: "photos/2024/a.jpg", want: true},
+		{name: "exact match", patterns: []string{"photos/a.jpg"}, value: "photos/a.jpg", want: true},
+		{name: "no match", patterns: []string{"videos/*"}, value: "photos/a.jpg", want: false},
+		{name: "double star", patterns: []string{"**/a.jpg"}, value: "photos/2024/a.jpg", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesAny(tt.patterns, tt.value); got != tt.want {
+				t.Errorf("matchesAny(%v, %q) = %v, want %v", tt.patterns, tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	defaultBatchMaxParallel = 8
+	maxBatchMaxParallel     = 64
+
+	// maxBatchItems bounds how many items a single /presign/batch call may
+	// submit, so a client can't force the server to spawn an unbounded
+	// number of goroutines up front.
+	maxBatchItems = 500
+)
+
+// BatchItem mirrors the single-presign request fields, plus which
+// operation to presign.
+type BatchItem struct {
+	Bucket  string `json:"bucket"`
+	Folder  string `json:"folder"`
+	Key     string `json:"key"`
+	Days    int    `json:"days"`
+	Hours   int    `json:"hours"`
+	Minutes int    `json:"minutes"`
+	Op      string `json:"op"` // "get" (default) or "put"
+}
+
+type BatchRequest struct {
+	Items       []BatchItem `json:"items"`
+	MaxParallel int         `json:"max_parallel"`
+}
+
+type BatchItemResult struct {
+	Index     int    `json:"index"`
+	URL       string `json:"url,omitempty"`
+	Object    string `json:"object"`
+	Bucket    string `json:"bucket"`
+	ExpiresIn string `json:"expires_in,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+}
+
+// batchHandler presigns many items in one call using a bounded worker pool.
+// Per-item failures (bad op, denied by the authorizer, presign error) are
+// reported in that item's Error field; only request-level problems (bad
+// JSON, no items) fail the whole call.
+func batchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid JSON body"})
+		return
+	}
+	if len(req.Items) == 0 {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "items is required"})
+		return
+	}
+	if len(req.Items) > maxBatchItems {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("items exceeds the %d-item limit per batch call", maxBatchItems)})
+		return
+	}
+
+	maxParallel := clampMaxParallel(req.MaxParallel)
+	results := make([]BatchItemResult, len(req.Items))
+
+	// Fixed worker pool reading from a channel, so the number of in-flight
+	// goroutines is bounded by maxParallel rather than len(req.Items).
+	type indexedItem struct {
+		index int
+		item  BatchItem
+	}
+	work := make(chan indexedItem)
+	var wg sync.WaitGroup
+	for i := 0; i < maxParallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for w := range work {
+				results[w.index] = presignBatchItem(r, w.index, w.item)
+			}
+		}()
+	}
+	for i, item := range req.Items {
+		work <- indexedItem{index: i, item: item}
+	}
+	close(work)
+	wg.Wait()
+
+	writeJSON(w, http.StatusOK, BatchResponse{Results: results})
+}
+
+func clampMaxParallel(n int) int {
+	if n <= 0 {
+		return defaultBatchMaxParallel
+	}
+	if n > maxBatchMaxParallel {
+		return maxBatchMaxParallel
+	}
+	return n
+}
+
+func presignBatchItem(r *http.Request, index int, item BatchItem) BatchItemResult {
+	bucket := item.Bucket
+	objectPath := joinObjectPath(item.Folder, item.Key)
+	result := BatchItemResult{Index: index, Object: objectPath, Bucket: bucket}
+
+	if bucket == "" || item.Key == "" {
+		result.Error = "bucket and key are required"
+		return result
+	}
+
+	op := item.Op
+	if op == "" {
+		op = "get"
+	}
+	if op != "get" && op != "put" {
+		result.Error = fmt.Sprintf("unsupported op %q (want \"get\" or \"put\")", op)
+		return result
+	}
+
+	expiry, err := expireDuration(item.Days, item.Hours, item.Minutes)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if err := authorizeRequest(r, bucket, objectPath, op, expiry); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	p, tenantID, err := resolvePresigner(r.Host, bucket)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var urlStr string
+	switch op {
+	case "get":
+		urlStr, err = p.PresignGet(r.Context(), bucket, objectPath, expiry, ResponseHeaderOverrides{})
+	case "put":
+		urlStr, err = p.PresignPut(r.Context(), bucket, objectPath, expiry)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.URL = rewriteForTenant(urlStr, tenantID)
+	result.ExpiresIn = expiry.String()
+	return result
+}
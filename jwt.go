@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// jwtHeader is the subset of the JOSE header this service cares about.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// parsedJWT is a decoded-but-not-yet-verified JWT.
+type parsedJWT struct {
+	Header       jwtHeader
+	Claims       map[string]any
+	SigningInput string // "<header>.<payload>" exactly as it appeared in the token
+	Signature    []byte
+}
+
+func parseJWTString(token string) (*parsedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT: expected 3 segments")
+	}
+
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT header: %w", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, fmt.Errorf("parsing JWT header: %w", err)
+	}
+
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT claims: %w", err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(claimsBytes, &claims); err != nil {
+		return nil, fmt.Errorf("parsing JWT claims: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decoding JWT signature: %w", err)
+	}
+
+	return &parsedJWT{
+		Header:       header,
+		Claims:       claims,
+		SigningInput: parts[0] + "." + parts[1],
+		Signature:    sig,
+	}, nil
+}
+
+func verifyRS256(signingInput string, sig []byte, pub *rsa.PublicKey) error {
+	h := sha256.Sum256([]byte(signingInput))
+	return rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig)
+}
+
+func verifyES256(signingInput string, sig []byte, pub *ecdsa.PublicKey) error {
+	if len(sig) != 64 {
+		return errors.New("invalid ES256 signature length")
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	h := sha256.Sum256([]byte(signingInput))
+	if !ecdsa.Verify(pub, h[:], r, s) {
+		return errors.New("invalid ES256 signature")
+	}
+	return nil
+}
+
+// claimExpired reports true if the claims have no usable "exp" or it has passed.
+func claimExpired(claims map[string]any) bool {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return true
+	}
+	return time.Now().After(time.Unix(int64(exp), 0))
+}
+
+// signHS256 mints a compact JWT signed with an HMAC-SHA256 secret. Used only
+// for the short-lived service tokens this server issues itself.
+func signHS256(claims map[string]any, secret []byte) (string, error) {
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	sig := mac.Sum(nil)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// verifyHS256 checks a service token's signature and expiry, returning its claims.
+func verifyHS256(token string, secret []byte) (map[string]any, error) {
+	parsed, err := parseJWTString(token)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Header.Alg != "HS256" {
+		return nil, fmt.Errorf("unexpected alg %q for service token", parsed.Header.Alg)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parsed.SigningInput))
+	expected := mac.Sum(nil)
+	if !hmac.Equal(expected, parsed.Signature) {
+		return nil, errors.New("invalid service token signature")
+	}
+
+	if claimExpired(parsed.Claims) {
+		return nil, errors.New("service token expired")
+	}
+
+	return parsed.Claims, nil
+}
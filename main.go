@@ -4,13 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 )
@@ -22,6 +19,14 @@ type PresignRequest struct {
 	Days    int    `json:"days"`
 	Hours   int    `json:"hours"`
 	Minutes int    `json:"minutes"`
+
+	// Optional S3-style response header overrides, applied as query params
+	// on the presigned URL so the downstream GET returns with these headers.
+	ResponseContentType        string `json:"response_content_type"`
+	ResponseContentDisposition string `json:"response_content_disposition"`
+	ResponseCacheControl       string `json:"response_cache_control"`
+	ResponseExpires            string `json:"response_expires"`
+	ResponseContentEncoding    string `json:"response_content_encoding"`
 }
 
 type PresignResponse struct {
@@ -29,18 +34,80 @@ type PresignResponse struct {
 	Object    string `json:"object"`
 	Bucket    string `json:"bucket"`
 	ExpiresIn string `json:"expires_in"`
+	Tenant    string `json:"tenant,omitempty"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// UploadRequest is the shared request body for /presign/upload and
+// /presign/post-policy. ContentType, MaxContentLength and Metadata only
+// apply to the post-policy form; PresignPut ignores them.
+type UploadRequest struct {
+	Bucket           string            `json:"bucket"`
+	Folder           string            `json:"folder"` // optional
+	Key              string            `json:"key"`    // required (file name, object key, or "prefix*" for post-policy)
+	Days             int               `json:"days"`
+	Hours            int               `json:"hours"`
+	Minutes          int               `json:"minutes"`
+	ContentType      string            `json:"content_type"`
+	MaxContentLength int64             `json:"max_content_length"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+type UploadResponse struct {
+	URL       string `json:"url"`
+	Object    string `json:"object"`
+	Bucket    string `json:"bucket"`
+	ExpiresIn string `json:"expires_in"`
+	Tenant    string `json:"tenant,omitempty"`
+}
+
+type PostPolicyResponse struct {
+	URL       string            `json:"url"`
+	FormData  map[string]string `json:"form_data"`
+	Object    string            `json:"object"`
+	Bucket    string            `json:"bucket"`
+	ExpiresIn string            `json:"expires_in"`
+	Tenant    string            `json:"tenant,omitempty"`
+}
+
+var (
+	presigner  Presigner
+	authorizer Authorizer
+	tenants    *tenantRegistry
+)
+
 func main() {
 	port := getenv("PORT", "8080")
 
+	var err error
+	presigner, err = newPresignerFromEnv()
+	if err != nil {
+		log.Fatalf("configuring presigner: %v", err)
+	}
+
+	authorizer, err = newAuthorizerFromEnv()
+	if err != nil {
+		log.Fatalf("configuring authorizer: %v", err)
+	}
+
+	if tenantsFilePath := getenv("TENANTS_FILE", ""); tenantsFilePath != "" {
+		tenants, err = newTenantRegistry(tenantsFilePath, getenv("TENANTS_PARENT_DOMAIN", ""))
+		if err != nil {
+			log.Fatalf("configuring tenants: %v", err)
+		}
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/sts/assume", stsAssumeHandler)
+	mux.HandleFunc("/tenants", tenantsAdminHandler)
 	mux.HandleFunc("/presign", presignHandler)
+	mux.HandleFunc("/presign/upload", uploadHandler)
+	mux.HandleFunc("/presign/post-policy", postPolicyHandler)
+	mux.HandleFunc("/presign/batch", batchHandler)
 
 	srv := &http.Server{
 		Addr:              ":" + port,
@@ -94,100 +161,80 @@ func presignHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alias := getenv("MINIO_ALIAS", "myminio")
-
 	// Build object path: folder + key (folder optional)
 	objectPath := joinObjectPath(req.Folder, req.Key)
-	target := fmt.Sprintf("%s/%s/%s", alias, req.Bucket, objectPath)
 
-	expire := buildExpire(req.Days, req.Hours, req.Minutes)
-	if expire == "" {
-		// sensible default if caller sends 0,0,0
-		expire = "15m"
+	expiry, err := expireDuration(req.Days, req.Hours, req.Minutes)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
 	}
 
-	// Run: mc share download --expire 10m myminio/bucket/path/to/file
-	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "mc", "share", "download", "--expire", expire, target)
-	outBytes, cmdErr := cmd.CombinedOutput()
-	out := string(outBytes)
-
-	if ctx.Err() == context.DeadlineExceeded {
-		writeJSON(w, http.StatusGatewayTimeout, ErrorResponse{Error: "mc command timed out"})
+	overrides := ResponseHeaderOverrides{
+		ContentType:        req.ResponseContentType,
+		ContentDisposition: req.ResponseContentDisposition,
+		CacheControl:       req.ResponseCacheControl,
+		Expires:            req.ResponseExpires,
+		ContentEncoding:    req.ResponseContentEncoding,
+	}
+	if err := overrides.Validate(); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	if cmdErr != nil {
-		// Return the mc error output (trimmed) to help debugging in n8n
-		msg := strings.TrimSpace(out)
-		if msg == "" {
-			msg = cmdErr.Error()
-		}
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: msg})
+
+	if err := authorizeRequest(r, req.Bucket, objectPath, "get", expiry); err != nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Extract the URL from the "Share:" line (this is the presigned URL).
-	urlStr, err := extractShareURL(out)
+	p, tenantID, err := resolvePresigner(r.Host, req.Bucket)
 	if err != nil {
-		// include mc output to make debugging easy
-		writeJSON(w, http.StatusBadRequest, ErrorResponse{
-			Error: "could not parse Share URL. mc output: " + strings.TrimSpace(out),
-		})
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Optionally rewrite returned URL to a public hostname users can access.
-	// Use this if mc outputs an internal hostname.
-	// e.g. PUBLIC_MINIO_BASE_URL=https://minio2.evatefinance.com
-	urlStr = rewritePublicBase(urlStr)
+	urlStr, err := p.PresignGet(r.Context(), req.Bucket, objectPath, expiry, overrides)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	urlStr = rewriteForTenant(urlStr, tenantID)
 
 	writeJSON(w, http.StatusOK, PresignResponse{
 		URL:       urlStr,
 		Object:    objectPath,
 		Bucket:    req.Bucket,
-		ExpiresIn: expire,
+		ExpiresIn: expiry.String(),
+		Tenant:    tenantID,
 	})
 }
 
 func withAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow /health without auth
-		if r.URL.Path == "/health" {
+		// Allow /health, the STS token exchange, and the tenant admin
+		// endpoint without the usual auth: /sts/assume authenticates the
+		// caller's IdP token itself, and /tenants is gated by ADMIN_TOKEN.
+		if r.URL.Path == "/health" || r.URL.Path == "/sts/assume" || r.URL.Path == "/tenants" {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		token := strings.TrimSpace(os.Getenv("API_TOKEN"))
-		if token == "" {
-			// If API_TOKEN not set, deny by default (safer)
-			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "API_TOKEN is not set on server"})
-			return
-		}
-
 		// Support either:
-		// 1) x-api-token: <token>
-		// 2) Authorization: Bearer <token>
-		h1 := strings.TrimSpace(r.Header.Get("x-api-token"))
-		h2 := strings.TrimSpace(r.Header.Get("Authorization"))
-
-		ok := false
-		if h1 != "" && h1 == token {
-			ok = true
-		}
-		if !ok && strings.HasPrefix(strings.ToLower(h2), "bearer ") {
-			if strings.TrimSpace(h2[7:]) == token {
-				ok = true
-			}
+		// 1) x-api-token: <legacy API_TOKEN>
+		// 2) Authorization: Bearer <legacy API_TOKEN or STS-issued service token>
+		token := strings.TrimSpace(r.Header.Get("x-api-token"))
+		if token == "" {
+			token = bearerToken(r)
 		}
 
+		subject, ok := authenticate(token)
 		if !ok {
 			writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), ctxKeySubject, subject)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -205,28 +252,6 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
-func buildExpire(days, hours, minutes int) string {
-	if days < 0 || hours < 0 || minutes < 0 {
-		return ""
-	}
-	if days == 0 && hours == 0 && minutes == 0 {
-		return ""
-	}
-
-	// mc accepts composite durations like "2d3h15m"
-	sb := strings.Builder{}
-	if days > 0 {
-		sb.WriteString(fmt.Sprintf("%dd", days))
-	}
-	if hours > 0 {
-		sb.WriteString(fmt.Sprintf("%dh", hours))
-	}
-	if minutes > 0 {
-		sb.WriteString(fmt.Sprintf("%dm", minutes))
-	}
-	return sb.String()
-}
-
 func joinObjectPath(folder, key string) string {
 	clean := func(s string) string {
 		s = strings.TrimSpace(s)
@@ -242,42 +267,6 @@ func joinObjectPath(folder, key string) string {
 	return f + "/" + k
 }
 
-// extractShareURL returns the presigned URL printed on the "Share:" line by `mc share download`.
-func extractShareURL(out string) (string, error) {
-	for _, line := range strings.Split(out, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Share:") {
-			u := strings.TrimSpace(strings.TrimPrefix(line, "Share:"))
-			if u == "" {
-				return "", errors.New("Share line present but empty")
-			}
-			return u, nil
-		}
-	}
-	return "", errors.New("could not find Share: line in mc output")
-}
-
-func rewritePublicBase(u string) string {
-	publicBase := strings.TrimSpace(os.Getenv("PUBLIC_MINIO_BASE_URL"))
-	if publicBase == "" {
-		return u
-	}
-
-	parsed, err := url.Parse(u)
-	if err != nil {
-		return u
-	}
-	pub, err := url.Parse(publicBase)
-	if err != nil {
-		return u
-	}
-
-	// Keep the path + query, swap only scheme/host.
-	parsed.Scheme = pub.Scheme
-	parsed.Host = pub.Host
-	return parsed.String()
-}
-
 func getenv(k, def string) string {
 	v := strings.TrimSpace(os.Getenv(k))
 	if v == "" {
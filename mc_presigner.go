@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// mcPresigner shells out to the `mc` CLI (`mc share download`) and scrapes
+// the presigned URL from its output. Kept only for backward compatibility
+// via PRESIGN_BACKEND=mc; prefer minioPresigner for new deployments.
+type mcPresigner struct {
+	alias string
+
+	// skipPublicRewrite is true for tenant-scoped instances (see
+	// newMcPresignerWithAlias), whose URLs are rewritten by the handler via
+	// rewriteForTenant using the tenant's own public_base_url. Applying the
+	// global PUBLIC_MINIO_BASE_URL rewrite first would point the URL at the
+	// wrong (single-tenant) host before the tenant rewrite ever runs.
+	skipPublicRewrite bool
+}
+
+func newMcPresigner() *mcPresigner {
+	return &mcPresigner{alias: getenv("MINIO_ALIAS", "myminio")}
+}
+
+// newMcPresignerWithAlias builds an mcPresigner for an explicit alias, so
+// per-tenant configs (see tenants.go) can each target their own `mc alias`.
+// Tenant URLs are rewritten via the tenant's own public_base_url instead of
+// the global PUBLIC_MINIO_BASE_URL, so the env-based rewrite is skipped here.
+func newMcPresignerWithAlias(alias string) *mcPresigner {
+	return &mcPresigner{alias: alias, skipPublicRewrite: true}
+}
+
+func (p *mcPresigner) PresignGet(ctx context.Context, bucket, object string, expiry time.Duration, overrides ResponseHeaderOverrides) (string, error) {
+	target := fmt.Sprintf("%s/%s/%s", p.alias, bucket, object)
+	expire := formatMcDuration(expiry)
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "mc", "share", "download", "--expire", expire, target)
+	outBytes, cmdErr := cmd.CombinedOutput()
+	out := string(outBytes)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("mc command timed out")
+	}
+	if cmdErr != nil {
+		msg := strings.TrimSpace(out)
+		if msg == "" {
+			msg = cmdErr.Error()
+		}
+		return "", errors.New(msg)
+	}
+
+	urlStr, err := extractShareURL(out)
+	if err != nil {
+		return "", fmt.Errorf("could not parse Share URL. mc output: %s", strings.TrimSpace(out))
+	}
+
+	if !p.skipPublicRewrite {
+		urlStr = rewritePublicBase(urlStr)
+	}
+	return appendResponseOverrides(urlStr, overrides)
+}
+
+// appendResponseOverrides adds the response-header query params to a URL
+// mc already produced, since `mc share download` has no flag for them.
+func appendResponseOverrides(u string, overrides ResponseHeaderOverrides) (string, error) {
+	extra := overrides.QueryValues()
+	if len(extra) == 0 {
+		return u, nil
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("parsing presigned URL: %w", err)
+	}
+
+	q := parsed.Query()
+	for k, vals := range extra {
+		for _, v := range vals {
+			q.Set(k, v)
+		}
+	}
+	parsed.RawQuery = q.Encode()
+	return parsed.String(), nil
+}
+
+func (p *mcPresigner) PresignPut(ctx context.Context, bucket, object string, expiry time.Duration) (string, error) {
+	return "", fmt.Errorf("presigned upload URLs are not supported with PRESIGN_BACKEND=mc; switch to the minio backend")
+}
+
+func (p *mcPresigner) PostPolicy(ctx context.Context, params PostPolicyParams) (PostPolicyResult, error) {
+	return PostPolicyResult{}, fmt.Errorf("presigned post-policy uploads are not supported with PRESIGN_BACKEND=mc; switch to the minio backend")
+}
+
+// formatMcDuration renders a time.Duration as the composite "2d3h15m" form
+// accepted by `mc share download --expire`.
+func formatMcDuration(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	hours := int(d / time.Hour)
+	d -= time.Duration(hours) * time.Hour
+	minutes := int(d / time.Minute)
+
+	sb := strings.Builder{}
+	if days > 0 {
+		fmt.Fprintf(&sb, "%dd", days)
+	}
+	if hours > 0 {
+		fmt.Fprintf(&sb, "%dh", hours)
+	}
+	if minutes > 0 || sb.Len() == 0 {
+		fmt.Fprintf(&sb, "%dm", minutes)
+	}
+	return sb.String()
+}
+
+// extractShareURL returns the presigned URL printed on the "Share:" line by `mc share download`.
+func extractShareURL(out string) (string, error) {
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Share:") {
+			u := strings.TrimSpace(strings.TrimPrefix(line, "Share:"))
+			if u == "" {
+				return "", errors.New("Share line present but empty")
+			}
+			return u, nil
+		}
+	}
+	return "", errors.New("could not find Share: line in mc output")
+}
+
+// rewritePublicBase swaps the scheme/host of a presigned URL for
+// PUBLIC_MINIO_BASE_URL, useful when mc's configured alias points at an
+// internal hostname. Not needed by minioPresigner, whose MINIO_ENDPOINT is
+// expected to already be the public one.
+func rewritePublicBase(u string) string {
+	publicBase := getenv("PUBLIC_MINIO_BASE_URL", "")
+	if publicBase == "" {
+		return u
+	}
+	rewritten, err := rewriteURLBase(u, publicBase)
+	if err != nil {
+		return u
+	}
+	return rewritten
+}
+
+// rewriteURLBase swaps the scheme/host of a presigned URL for publicBase,
+// keeping the path and query intact. Used directly by tenant routing
+// (tenants.go), which rewrites per-tenant rather than off a single env var.
+func rewriteURLBase(u, publicBase string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+	pub, err := url.Parse(publicBase)
+	if err != nil {
+		return "", err
+	}
+
+	parsed.Scheme = pub.Scheme
+	parsed.Host = pub.Host
+	return parsed.String(), nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// minioPresigner generates presigned URLs directly via the minio-go SDK,
+// without forking a subprocess. Configured entirely from the MINIO_* env
+// vars so the server can talk straight to the endpoint the caller should
+// already be able to reach (no rewritePublicBase needed).
+type minioPresigner struct {
+	client *minio.Client
+}
+
+func newMinioPresigner() (*minioPresigner, error) {
+	return newMinioPresignerWithConfig(
+		getenv("MINIO_ENDPOINT", ""),
+		getenv("MINIO_ACCESS_KEY", ""),
+		getenv("MINIO_SECRET_KEY", ""),
+		getenv("MINIO_REGION", ""),
+		getenv("MINIO_USE_SSL", "true") == "true",
+	)
+}
+
+// newMinioPresignerWithConfig builds a minioPresigner from explicit
+// credentials rather than the MINIO_* env vars, so per-tenant configs
+// (see tenants.go) can each get their own client.
+func newMinioPresignerWithConfig(endpoint, accessKey, secretKey, region string, useSSL bool) (*minioPresigner, error) {
+	if endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("access key and secret key are required")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating minio client: %w", err)
+	}
+
+	return &minioPresigner{client: client}, nil
+}
+
+func (p *minioPresigner) PresignGet(ctx context.Context, bucket, object string, expiry time.Duration, overrides ResponseHeaderOverrides) (string, error) {
+	u, err := p.client.PresignedGetObject(ctx, bucket, object, expiry, overrides.QueryValues())
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (p *minioPresigner) PresignPut(ctx context.Context, bucket, object string, expiry time.Duration) (string, error) {
+	u, err := p.client.PresignedPutObject(ctx, bucket, object, expiry)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// objectKeyStartsWith reports whether object is a prefix match rather than an
+// exact key, using the same trailing-"*" convention as the STS policy globs.
+func objectKeyStartsWith(object string) (prefix string, isPrefix bool) {
+	if strings.HasSuffix(object, "*") {
+		return strings.TrimSuffix(object, "*"), true
+	}
+	return object, false
+}
+
+func (p *minioPresigner) PostPolicy(ctx context.Context, params PostPolicyParams) (PostPolicyResult, error) {
+	policy := minio.NewPostPolicy()
+
+	if err := policy.SetBucket(params.Bucket); err != nil {
+		return PostPolicyResult{}, fmt.Errorf("setting bucket: %w", err)
+	}
+
+	if prefix, isPrefix := objectKeyStartsWith(params.Object); isPrefix {
+		if err := policy.SetKeyStartsWith(prefix); err != nil {
+			return PostPolicyResult{}, fmt.Errorf("setting key prefix: %w", err)
+		}
+	} else if err := policy.SetKey(params.Object); err != nil {
+		return PostPolicyResult{}, fmt.Errorf("setting key: %w", err)
+	}
+
+	if err := policy.SetExpires(time.Now().UTC().Add(params.Expiry)); err != nil {
+		return PostPolicyResult{}, fmt.Errorf("setting expiry: %w", err)
+	}
+
+	if params.MaxContentLength > 0 {
+		if err := policy.SetContentLengthRange(1, params.MaxContentLength); err != nil {
+			return PostPolicyResult{}, fmt.Errorf("setting content length range: %w", err)
+		}
+	}
+
+	if params.ContentType != "" {
+		if err := policy.SetContentType(params.ContentType); err != nil {
+			return PostPolicyResult{}, fmt.Errorf("setting content type: %w", err)
+		}
+	}
+
+	for k, v := range params.Metadata {
+		if err := policy.SetUserMetadata(k, v); err != nil {
+			return PostPolicyResult{}, fmt.Errorf("setting metadata %q: %w", k, err)
+		}
+	}
+
+	u, formData, err := p.client.PresignedPostPolicy(ctx, policy)
+	if err != nil {
+		return PostPolicyResult{}, err
+	}
+
+	return PostPolicyResult{URL: u.String(), FormData: formData}, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ctxKey namespaces values stored on request contexts by this package.
+type ctxKey string
+
+// ctxKeySubject holds the authenticated subject (from withAuth) for use by
+// the per-endpoint Authorizer checks.
+const ctxKeySubject ctxKey = "subject"
+
+// legacySubject is the subject recorded for requests authenticated with the
+// static API_TOKEN, so allowlist/OPA policies can reference it explicitly.
+const legacySubject = "legacy-api-token"
+
+type AssumeRoleResponse struct {
+	Token     string `json:"token"`
+	Subject   string `json:"subject"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+// stsAssumeHandler exchanges a caller-presented OIDC/JWT bearer token for a
+// short-lived service token, mirroring AWS STS AssumeRoleWithClientGrants.
+func stsAssumeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	idToken := bearerToken(r)
+	if idToken == "" {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	jwksURL := getenv("STS_JWKS_URL", "")
+	if jwksURL == "" {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "STS_JWKS_URL is not set on server"})
+		return
+	}
+
+	expectedAudience := getenv("STS_EXPECTED_AUDIENCE", "")
+	if expectedAudience == "" {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "STS_EXPECTED_AUDIENCE is not set on server"})
+		return
+	}
+	expectedIssuer := getenv("STS_EXPECTED_ISSUER", "")
+
+	claims, err := verifyIDToken(r.Context(), idToken, jwksURL, expectedIssuer, expectedAudience)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "id token missing sub claim"})
+		return
+	}
+
+	ttl := stsTokenTTL()
+	serviceToken, err := issueServiceToken(sub, claims, ttl)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, AssumeRoleResponse{
+		Token:     serviceToken,
+		Subject:   sub,
+		ExpiresIn: ttl.String(),
+	})
+}
+
+// verifyIDToken validates an RS256- or ES256-signed bearer token against the
+// IdP's JWKS and returns its claims. It also enforces that the token was
+// minted for this service: aud must contain expectedAudience (required, so a
+// token valid for some other client of the same IdP can't be replayed here),
+// and iss must equal expectedIssuer when one is configured.
+func verifyIDToken(ctx context.Context, token, jwksURL, expectedIssuer, expectedAudience string) (map[string]any, error) {
+	parsed, err := parseJWTString(token)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := fetchJWKS(ctx, jwksURL)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := set.find(parsed.Header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsed.Header.Alg {
+	case "RS256":
+		pub, err := key.rsaPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyRS256(parsed.SigningInput, parsed.Signature, pub); err != nil {
+			return nil, fmt.Errorf("invalid RS256 signature: %w", err)
+		}
+	case "ES256":
+		pub, err := key.ecdsaPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyES256(parsed.SigningInput, parsed.Signature, pub); err != nil {
+			return nil, fmt.Errorf("invalid ES256 signature: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT alg %q", parsed.Header.Alg)
+	}
+
+	if claimExpired(parsed.Claims) {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	if !claimAudienceContains(parsed.Claims, expectedAudience) {
+		return nil, fmt.Errorf("token aud does not include expected audience %q", expectedAudience)
+	}
+
+	if expectedIssuer != "" {
+		iss, _ := parsed.Claims["iss"].(string)
+		if iss != expectedIssuer {
+			return nil, fmt.Errorf("token iss %q does not match expected issuer %q", iss, expectedIssuer)
+		}
+	}
+
+	return parsed.Claims, nil
+}
+
+// claimAudienceContains reports whether the "aud" claim (either a single
+// string or an array of strings, per RFC 7519) contains want.
+func claimAudienceContains(claims map[string]any, want string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == want
+	case []any:
+		for _, v := range aud {
+			if s, ok := v.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func stsTokenTTL() time.Duration {
+	d, err := time.ParseDuration(getenv("STS_TOKEN_TTL", "15m"))
+	if err != nil || d <= 0 {
+		return 15 * time.Minute
+	}
+	return d
+}
+
+func stsSigningKey() []byte {
+	return []byte(getenv("STS_SIGNING_KEY", ""))
+}
+
+// issueServiceToken mints a short-lived HS256 JWT carrying sub, exp, and the
+// caller's IdP claims (for downstream policy decisions), signed with
+// STS_SIGNING_KEY.
+func issueServiceToken(sub string, idClaims map[string]any, ttl time.Duration) (string, error) {
+	secret := stsSigningKey()
+	if len(secret) == 0 {
+		return "", fmt.Errorf("STS_SIGNING_KEY is not set on server")
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"sub":    sub,
+		"iat":    now.Unix(),
+		"exp":    now.Add(ttl).Unix(),
+		"claims": idClaims,
+	}
+	return signHS256(claims, secret)
+}
+
+// bearerToken extracts the raw token from an `Authorization: Bearer ...` header.
+func bearerToken(r *http.Request) string {
+	h := strings.TrimSpace(r.Header.Get("Authorization"))
+	if !strings.HasPrefix(strings.ToLower(h), "bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[len("bearer "):])
+}
+
+// authenticate accepts either the legacy static API_TOKEN or a service token
+// issued by stsAssumeHandler, returning the subject to authorize as.
+func authenticate(token string) (string, bool) {
+	if apiToken := getenv("API_TOKEN", ""); apiToken != "" && token == apiToken {
+		return legacySubject, true
+	}
+
+	secret := stsSigningKey()
+	if len(secret) == 0 {
+		return "", false
+	}
+
+	claims, err := verifyHS256(token, secret)
+	if err != nil {
+		return "", false
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", false
+	}
+	return sub, true
+}
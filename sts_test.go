@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://idp.example.com/"
+	testAudience = "minio-presign-service"
+)
+
+// testJWKSServer serves a JWKS containing the given RSA/EC public keys under
+// the given kids, for fetchJWKS to hit during verifyIDToken tests.
+func testJWKSServer(t *testing.T, rsaKey *rsa.PrivateKey, rsaKid string, ecKey *ecdsa.PrivateKey, ecKid string) *httptest.Server {
+	t.Helper()
+	set := jwkSet{}
+	if rsaKey != nil {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: rsaKid,
+			N:   base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		})
+	}
+	if ecKey != nil {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "EC",
+			Kid: ecKid,
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+			Y:   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+		})
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+}
+
+func encodeSegment(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signRS256Token(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signingInput := encodeSegment(jwtHeader{Alg: "RS256", Kid: kid, Typ: "JWT"}) + "." + encodeSegment(claims)
+	h := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		t.Fatalf("signing RS256 test token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signES256Token(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	signingInput := encodeSegment(jwtHeader{Alg: "ES256", Kid: kid, Typ: "JWT"}) + "." + encodeSegment(claims)
+	h := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, h[:])
+	if err != nil {
+		t.Fatalf("signing ES256 test token: %v", err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func baseClaims() map[string]any {
+	return map[string]any{
+		"sub": "user-123",
+		"iss": testIssuer,
+		"aud": testAudience,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating second RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+
+	srv := testJWKSServer(t, rsaKey, "rsa-1", ecKey, "ec-1")
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{
+			name:  "valid RS256",
+			token: signRS256Token(t, rsaKey, "rsa-1", baseClaims()),
+		},
+		{
+			name:  "valid ES256",
+			token: signES256Token(t, ecKey, "ec-1", baseClaims()),
+		},
+		{
+			name:    "wrong key",
+			token:   signRS256Token(t, otherRSAKey, "rsa-1", baseClaims()),
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := baseClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return signRS256Token(t, rsaKey, "rsa-1", claims)
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := baseClaims()
+				claims["aud"] = "some-other-service"
+				return signRS256Token(t, rsaKey, "rsa-1", claims)
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := baseClaims()
+				claims["iss"] = "https://attacker.example.com/"
+				return signRS256Token(t, rsaKey, "rsa-1", claims)
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "missing audience claim",
+			token: func() string {
+				claims := baseClaims()
+				delete(claims, "aud")
+				return signRS256Token(t, rsaKey, "rsa-1", claims)
+			}(),
+			wantErr: true,
+		},
+		{
+			name:    "unsupported alg",
+			token:   encodeSegment(jwtHeader{Alg: "none", Kid: "rsa-1"}) + "." + encodeSegment(baseClaims()) + ".",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifyIDToken(context.Background(), tt.token, srv.URL, testIssuer, testAudience)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("verifyIDToken(%q) = nil error, want error", tt.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyIDToken(%q) = %v, want success", tt.name, err)
+			}
+			if sub, _ := claims["sub"].(string); sub != "user-123" {
+				t.Errorf("sub = %q, want %q", sub, "user-123")
+			}
+		})
+	}
+}
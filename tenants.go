@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TenantConfig is one entry of TENANTS_FILE: the MinIO credentials and
+// routing info for a single tenant. Alias is used by the mc backend,
+// Endpoint/AccessKey/SecretKey/Region/UseSSL by the minio-go backend.
+type TenantConfig struct {
+	Alias          string   `json:"alias"`
+	Endpoint       string   `json:"endpoint"`
+	AccessKey      string   `json:"access_key"`
+	SecretKey      string   `json:"secret_key"`
+	Region         string   `json:"region"`
+	UseSSL         bool     `json:"use_ssl"`
+	PublicBaseURL  string   `json:"public_base_url"`
+	AllowedBuckets []string `json:"allowed_buckets"`
+}
+
+func (c TenantConfig) bucketAllowed(bucket string) bool {
+	if len(c.AllowedBuckets) == 0 {
+		return true
+	}
+	for _, pattern := range c.AllowedBuckets {
+		if ok, err := path.Match(pattern, bucket); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantsFile is the on-disk shape of TENANTS_FILE.
+type tenantsFile struct {
+	Tenants map[string]TenantConfig `json:"tenants"`
+}
+
+// tenantRegistry resolves a request's Host header to a TenantConfig and
+// caches the Presigner built from it, reloading from TENANTS_FILE on demand
+// via the /tenants admin endpoint.
+type tenantRegistry struct {
+	parentDomain string
+
+	mu         sync.RWMutex
+	tenants    map[string]TenantConfig
+	presigners map[string]Presigner
+}
+
+func newTenantRegistry(filePath, parentDomain string) (*tenantRegistry, error) {
+	r := &tenantRegistry{parentDomain: parentDomain}
+	if err := r.reload(filePath); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *tenantRegistry) reload(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("reading TENANTS_FILE: %w", err)
+	}
+
+	var tf tenantsFile
+	if err := json.Unmarshal(data, &tf); err != nil {
+		return fmt.Errorf("parsing TENANTS_FILE: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants = tf.Tenants
+	r.presigners = map[string]Presigner{} // drop cached presigners; creds may have changed
+	return nil
+}
+
+// tenantIDForHost strips the configured parent domain suffix off a request's
+// Host header to recover the tenant id, e.g. "acme.minio.example.com" with
+// parentDomain "minio.example.com" resolves to "acme".
+func (r *tenantRegistry) tenantIDForHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if r.parentDomain != "" && strings.HasSuffix(host, "."+r.parentDomain) {
+		return strings.TrimSuffix(host, "."+r.parentDomain)
+	}
+	return host
+}
+
+func (r *tenantRegistry) config(id string) (TenantConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	cfg, ok := r.tenants[id]
+	return cfg, ok
+}
+
+func (r *tenantRegistry) ids() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (r *tenantRegistry) presignerFor(id string) (Presigner, error) {
+	r.mu.RLock()
+	if p, ok := r.presigners[id]; ok {
+		r.mu.RUnlock()
+		return p, nil
+	}
+	cfg, ok := r.tenants[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown tenant %q", id)
+	}
+
+	p, err := newPresignerForTenant(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("configuring presigner for tenant %q: %w", id, err)
+	}
+
+	r.mu.Lock()
+	r.presigners[id] = p
+	r.mu.Unlock()
+	return p, nil
+}
+
+// resolvePresigner picks the Presigner to use for a request: the tenant
+// resolved from host when multi-tenant routing is configured (TENANTS_FILE),
+// or the singleton env-configured presigner otherwise. tenantID is "" in the
+// single-tenant case.
+func resolvePresigner(host, bucket string) (p Presigner, tenantID string, err error) {
+	if tenants == nil {
+		return presigner, "", nil
+	}
+
+	id := tenants.tenantIDForHost(host)
+	cfg, ok := tenants.config(id)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown tenant for host %q", host)
+	}
+	if !cfg.bucketAllowed(bucket) {
+		return nil, "", fmt.Errorf("bucket %q is not allowed for tenant %q", bucket, id)
+	}
+
+	p, err = tenants.presignerFor(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, id, nil
+}
+
+// rewriteForTenant applies the resolved tenant's public_base_url to a
+// presigned URL, if one is configured. No-op for the single-tenant case.
+func rewriteForTenant(urlStr, tenantID string) string {
+	if tenantID == "" {
+		return urlStr
+	}
+	cfg, ok := tenants.config(tenantID)
+	if !ok || cfg.PublicBaseURL == "" {
+		return urlStr
+	}
+	rewritten, err := rewriteURLBase(urlStr, cfg.PublicBaseURL)
+	if err != nil {
+		return urlStr
+	}
+	return rewritten
+}
+
+// newPresignerForTenant builds a Presigner for a single tenant, honoring the
+// same PRESIGN_BACKEND selection as the singleton env-configured one.
+func newPresignerForTenant(cfg TenantConfig) (Presigner, error) {
+	switch backend := getenv("PRESIGN_BACKEND", "minio"); backend {
+	case "minio":
+		return newMinioPresignerWithConfig(cfg.Endpoint, cfg.AccessKey, cfg.SecretKey, cfg.Region, cfg.UseSSL)
+	case "mc":
+		return newMcPresignerWithAlias(cfg.Alias), nil
+	default:
+		return nil, fmt.Errorf("unknown PRESIGN_BACKEND %q (want \"minio\" or \"mc\")", backend)
+	}
+}
+
+type TenantsListResponse struct {
+	Tenants []string `json:"tenants"`
+}
+
+// tenantsAdminHandler lists configured tenant ids (GET) or hot-reloads
+// TENANTS_FILE from disk (POST), gated by ADMIN_TOKEN since it's separate
+// from the per-subject /presign* authorization model.
+func tenantsAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if !requireAdminToken(r) {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "unauthorized"})
+		return
+	}
+	if tenants == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "multi-tenant routing is not configured (set TENANTS_FILE)"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, TenantsListResponse{Tenants: tenants.ids()})
+	case http.MethodPost:
+		if err := tenants.reload(getenv("TENANTS_FILE", "")); err != nil {
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, TenantsListResponse{Tenants: tenants.ids()})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+	}
+}
+
+// requireAdminToken checks the x-admin-token header (or Authorization:
+// Bearer) against ADMIN_TOKEN. Denies by default if ADMIN_TOKEN is unset.
+func requireAdminToken(r *http.Request) bool {
+	adminToken := getenv("ADMIN_TOKEN", "")
+	if adminToken == "" {
+		return false
+	}
+	got := strings.TrimSpace(r.Header.Get("x-admin-token"))
+	if got == "" {
+		got = bearerToken(r)
+	}
+	return got == adminToken
+}
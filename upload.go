@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+func uploadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	req, ok := decodeUploadRequest(w, r)
+	if !ok {
+		return
+	}
+
+	expiry, err := expireDuration(req.Days, req.Hours, req.Minutes)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	objectPath := joinObjectPath(req.Folder, req.Key)
+
+	if err := authorizeRequest(r, req.Bucket, objectPath, "put", expiry); err != nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	p, tenantID, err := resolvePresigner(r.Host, req.Bucket)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	urlStr, err := p.PresignPut(r.Context(), req.Bucket, objectPath, expiry)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	urlStr = rewriteForTenant(urlStr, tenantID)
+
+	writeJSON(w, http.StatusOK, UploadResponse{
+		URL:       urlStr,
+		Object:    objectPath,
+		Bucket:    req.Bucket,
+		ExpiresIn: expiry.String(),
+		Tenant:    tenantID,
+	})
+}
+
+func postPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "method not allowed"})
+		return
+	}
+
+	req, ok := decodeUploadRequest(w, r)
+	if !ok {
+		return
+	}
+
+	expiry, err := expireDuration(req.Days, req.Hours, req.Minutes)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	objectPath := joinObjectPath(req.Folder, req.Key)
+
+	if err := authorizeRequest(r, req.Bucket, objectPath, "put", expiry); err != nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	p, tenantID, err := resolvePresigner(r.Host, req.Bucket)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := p.PostPolicy(r.Context(), PostPolicyParams{
+		Bucket:           req.Bucket,
+		Object:           objectPath,
+		Expiry:           expiry,
+		ContentType:      req.ContentType,
+		MaxContentLength: req.MaxContentLength,
+		Metadata:         req.Metadata,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	result.URL = rewriteForTenant(result.URL, tenantID)
+
+	writeJSON(w, http.StatusOK, PostPolicyResponse{
+		URL:       result.URL,
+		FormData:  result.FormData,
+		Object:    objectPath,
+		Bucket:    req.Bucket,
+		ExpiresIn: expiry.String(),
+		Tenant:    tenantID,
+	})
+}
+
+// decodeUploadRequest decodes and validates the request body shared by the
+// /presign/upload and /presign/post-policy endpoints.
+func decodeUploadRequest(w http.ResponseWriter, r *http.Request) (UploadRequest, bool) {
+	var req UploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid JSON body"})
+		return UploadRequest{}, false
+	}
+
+	req.Bucket = strings.TrimSpace(req.Bucket)
+	req.Folder = strings.TrimSpace(req.Folder)
+	req.Key = strings.TrimSpace(req.Key)
+
+	if req.Bucket == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "bucket is required"})
+		return UploadRequest{}, false
+	}
+	if req.Key == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "key is required"})
+		return UploadRequest{}, false
+	}
+
+	return req, true
+}